@@ -0,0 +1,141 @@
+//go:build unix
+
+package fd
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// Conn wraps a *net.UnixConn to send and receive file descriptors
+// without dup'ing the connection on every call. Calling via.File() for
+// each transfer dups the fd, drops it into blocking mode and closes the
+// dup again; in a hot-upgrade flow that transfers hundreds of listeners
+// this is wasteful, and briefly
+// taking the original UnixConn out of non-blocking mode breaks any
+// concurrent I/O on it. Conn dups once in NewConn and otherwise drives
+// the original connection's fd through syscall.RawConn, so it stays
+// non-blocking and keeps working for ordinary reads and writes.
+type Conn struct {
+	via *net.UnixConn
+	rc  syscall.RawConn
+	fd  int
+}
+
+// NewConn wraps via for repeated fd transfers. The returned Conn owns a
+// dup of via's fd, kept open until Close, but all actual sendmsg/recvmsg
+// calls happen on via's own fd via syscall.RawConn so via stays usable
+// and non-blocking for the lifetime of the Conn.
+//
+// The dup is obtained through rc.Control rather than via.File(): File()
+// puts the returned *os.File, and in doing so via's shared underlying
+// file description, into blocking mode the moment its Fd() method is
+// called, which is exactly the side effect this type exists to avoid.
+//
+// The dup is taken with F_DUPFD_CLOEXEC rather than plain syscall.Dup so
+// it's marked close-on-exec atomically; otherwise a concurrent fork+exec
+// elsewhere in the process could inherit it in the window between the
+// dup and a separate fcntl(F_SETFD) call.
+func NewConn(via *net.UnixConn) (*Conn, error) {
+	rc, err := via.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var fd int
+	var dupErr error
+	if err := rc.Control(func(s uintptr) {
+		r, _, errno := syscall.Syscall(syscall.SYS_FCNTL, s, syscall.F_DUPFD_CLOEXEC, 0)
+		fd = int(r)
+		if errno != 0 {
+			dupErr = errno
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if dupErr != nil {
+		return nil, dupErr
+	}
+
+	return &Conn{via: via, rc: rc, fd: fd}, nil
+}
+
+// Put sends files over the connection. See the top-level Put for
+// details.
+func (c *Conn) Put(files ...*os.File) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	fds := make([]int, len(files))
+	for i := range files {
+		fds[i] = int(files[i].Fd())
+	}
+	rights := syscall.UnixRights(fds...)
+
+	var sendErr error
+	if err := c.rc.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return sendErr != syscall.EAGAIN
+	}); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+// Get receives files over the connection. See the top-level Get for
+// details.
+func (c *Conn) Get(num int, filenames []string) ([]*os.File, error) {
+	if num < 1 {
+		return nil, nil
+	}
+
+	buf := make([]byte, syscall.CmsgSpace(num*4))
+
+	var (
+		oobn    int
+		recvErr error
+	)
+	if err := c.rc.Read(func(fd uintptr) bool {
+		_, oobn, _, _, recvErr = syscall.Recvmsg(int(fd), nil, buf, recvmsgFlags)
+		return recvErr != syscall.EAGAIN
+	}); err != nil {
+		return nil, err
+	}
+	if recvErr != nil {
+		return nil, recvErr
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(buf[:oobn])
+	if err != nil {
+		return nil, err
+	}
+
+	var fds []int
+	for i := range msgs {
+		parsed, err := syscall.ParseUnixRights(&msgs[i])
+		if err != nil {
+			return nil, err
+		}
+		fds = append(fds, parsed...)
+	}
+	closeOnExec(fds)
+
+	res := make([]*os.File, 0, len(fds))
+	for fi, fdv := range fds {
+		var filename string
+		if fi < len(filenames) {
+			filename = filenames[fi]
+		}
+		res = append(res, os.NewFile(uintptr(fdv), filename))
+	}
+
+	return res, nil
+}
+
+// Close releases the Conn's dup of the underlying socket. It does not
+// close the *net.UnixConn passed to NewConn.
+func (c *Conn) Close() error {
+	return syscall.Close(c.fd)
+}
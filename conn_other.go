@@ -0,0 +1,30 @@
+//go:build !unix
+
+package fd
+
+import (
+	"net"
+	"os"
+)
+
+// Conn is not implemented on this platform; it relies on SCM_RIGHTS,
+// which is unix-specific.
+type Conn struct{}
+
+// NewConn is not implemented on this platform; it relies on SCM_RIGHTS,
+// which is unix-specific.
+func NewConn(via *net.UnixConn) (*Conn, error) {
+	return nil, ErrUnsupported
+}
+
+func (c *Conn) Put(files ...*os.File) error {
+	return ErrUnsupported
+}
+
+func (c *Conn) Get(num int, filenames []string) ([]*os.File, error) {
+	return nil, ErrUnsupported
+}
+
+func (c *Conn) Close() error {
+	return ErrUnsupported
+}
@@ -0,0 +1,68 @@
+//go:build unix
+
+package fd
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func isNonblocking(t *testing.T, via *net.UnixConn) bool {
+	t.Helper()
+
+	rc, err := via.SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn: %v", err)
+	}
+
+	var flags int
+	var fcntlErrno syscall.Errno
+	if err := rc.Control(func(fd uintptr) {
+		r, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+		flags, fcntlErrno = int(r), errno
+	}); err != nil {
+		t.Fatalf("Control: %v", err)
+	}
+	if fcntlErrno != 0 {
+		t.Fatalf("fcntl(F_GETFL): %v", fcntlErrno)
+	}
+
+	return flags&syscall.O_NONBLOCK != 0
+}
+
+func TestNewConnPreservesNonBlocking(t *testing.T) {
+	a, _ := socketpair(t)
+
+	if !isNonblocking(t, a) {
+		t.Fatal("net.UnixConn should start non-blocking")
+	}
+
+	c, err := NewConn(a)
+	if err != nil {
+		t.Fatalf("NewConn: %v", err)
+	}
+	defer c.Close()
+
+	if !isNonblocking(t, a) {
+		t.Fatal("NewConn left via in blocking mode")
+	}
+}
+
+func TestNewConnMarksDupCloseOnExec(t *testing.T) {
+	a, _ := socketpair(t)
+
+	c, err := NewConn(a)
+	if err != nil {
+		t.Fatalf("NewConn: %v", err)
+	}
+	defer c.Close()
+
+	r, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(c.fd), syscall.F_GETFD, 0)
+	if errno != 0 {
+		t.Fatalf("fcntl(F_GETFD): %v", errno)
+	}
+	if r&syscall.FD_CLOEXEC == 0 {
+		t.Fatal("Conn's internal dup'd fd is not close-on-exec")
+	}
+}
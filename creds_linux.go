@@ -0,0 +1,94 @@
+//go:build linux
+
+package fd
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// PutCreds enables SO_PASSCRED on the underlying socket so that the
+// kernel attaches the sender's credentials (pid, uid, gid) as
+// SCM_CREDENTIALS ancillary data to the next message read from it via
+// GetCreds or GetWithCreds. It must be called on the receiving end
+// before the peer sends anything it wants authenticated.
+func PutCreds(via *net.UnixConn) error {
+	rc, err := via.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+	if err := rc.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_PASSCRED, 1)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// GetCreds receives the peer's process ID, user ID and group ID sent as
+// SCM_CREDENTIALS ancillary data. PutCreds must have been called on this
+// end of the socket first, and the sender must be on the same host.
+func GetCreds(via *net.UnixConn) (pid, uid, gid uint32, err error) {
+	_, pid, uid, gid, err = getWithCreds(via, 0, nil)
+	return
+}
+
+// GetWithCreds is like Get, but also returns the sender's credentials
+// from the SCM_CREDENTIALS ancillary data attached to the same message,
+// so a server that inherits a socket can verify who it's talking to
+// before trusting any file descriptors it receives. PutCreds must have
+// been called on this end of the socket first.
+func GetWithCreds(via *net.UnixConn, num int, filenames []string) (files []*os.File, pid, uid, gid uint32, err error) {
+	return getWithCreds(via, num, filenames)
+}
+
+// getWithCreds does a single recvmsg call and demultiplexes whatever mix
+// of SCM_RIGHTS and SCM_CREDENTIALS control messages comes back.
+func getWithCreds(via *net.UnixConn, num int, filenames []string) (files []*os.File, pid, uid, gid uint32, err error) {
+	c, err := NewConn(via)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	defer c.Close()
+
+	buf := make([]byte, syscall.CmsgSpace(num*4)+syscall.CmsgSpace(syscall.SizeofUcred))
+	oobn, err := recvFrame(c, nil, buf)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(buf[:oobn])
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+
+	var fds []int
+	for i := range msgs {
+		if msgFds, ferr := syscall.ParseUnixRights(&msgs[i]); ferr == nil {
+			fds = append(fds, msgFds...)
+			continue
+		}
+
+		if cred, cerr := syscall.ParseUnixCredentials(&msgs[i]); cerr == nil {
+			pid, uid, gid = uint32(cred.Pid), uint32(cred.Uid), uint32(cred.Gid)
+		}
+	}
+
+	// a no-op on Linux, where recvFrame's MSG_CMSG_CLOEXEC already marked
+	// the fds close-on-exec
+	closeOnExec(fds)
+
+	res := make([]*os.File, 0, len(fds))
+	for fi, fdv := range fds {
+		var filename string
+		if fi < len(filenames) {
+			filename = filenames[fi]
+		}
+		res = append(res, os.NewFile(uintptr(fdv), filename))
+	}
+
+	return res, pid, uid, gid, nil
+}
@@ -0,0 +1,85 @@
+//go:build linux
+
+package fd
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPutGetCreds(t *testing.T) {
+	a, b := socketpair(t)
+
+	if err := PutCreds(b); err != nil {
+		t.Fatalf("PutCreds: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { _, err := a.Write([]byte{0}); done <- err }()
+
+	pid, uid, gid, err := GetCreds(b)
+	if err != nil {
+		t.Fatalf("GetCreds: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// uid/gid are stable identifiers we can compare directly; pid can
+	// differ from os.Getpid() under some sandboxes with virtualized pid
+	// namespaces, so just check it came back nonzero.
+	if pid == 0 {
+		t.Fatal("pid = 0, want nonzero")
+	}
+	if want := uint32(os.Getuid()); uid != want {
+		t.Fatalf("uid = %d, want %d", uid, want)
+	}
+	if want := uint32(os.Getgid()); gid != want {
+		t.Fatalf("gid = %d, want %d", gid, want)
+	}
+}
+
+func TestGetWithCredsRoundTrip(t *testing.T) {
+	a, b := socketpair(t)
+
+	if err := PutCreds(b); err != nil {
+		t.Fatalf("PutCreds: %v", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if _, err := w.WriteString("hi"); err != nil {
+		t.Fatalf("WriteString: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- Put(a, r) }()
+
+	files, pid, _, _, err := GetWithCreds(b, 1, nil)
+	if err != nil {
+		t.Fatalf("GetWithCreds: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d files, want 1", len(files))
+	}
+	defer files[0].Close()
+
+	if pid == 0 {
+		t.Fatal("pid = 0, want nonzero")
+	}
+
+	buf := make([]byte, 2)
+	if _, err := files[0].Read(buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(buf) != "hi" {
+		t.Fatalf("read %q, want %q", buf, "hi")
+	}
+}
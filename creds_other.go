@@ -0,0 +1,26 @@
+//go:build !linux
+
+package fd
+
+import (
+	"net"
+	"os"
+)
+
+// PutCreds is not implemented outside Linux; SCM_CREDENTIALS and
+// SO_PASSCRED are Linux-specific.
+func PutCreds(via *net.UnixConn) error {
+	return ErrUnsupported
+}
+
+// GetCreds is not implemented outside Linux; SCM_CREDENTIALS and
+// SO_PASSCRED are Linux-specific.
+func GetCreds(via *net.UnixConn) (pid, uid, gid uint32, err error) {
+	return 0, 0, 0, ErrUnsupported
+}
+
+// GetWithCreds is not implemented outside Linux; SCM_CREDENTIALS and
+// SO_PASSCRED are Linux-specific.
+func GetWithCreds(via *net.UnixConn, num int, filenames []string) (files []*os.File, pid, uid, gid uint32, err error) {
+	return nil, 0, 0, 0, ErrUnsupported
+}
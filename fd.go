@@ -16,14 +16,29 @@
 // - New copy reads the state and inherits connections using fd.Get(),
 //   checks that everything is OK and sends the "OK" message to the socket
 // - Server receives "OK" message and kills itself
+//
+// On Linux, a receiver that did not create the socket itself (e.g. one
+// that inherited it from a supervisor) can use PutCreds/GetCreds or
+// GetWithCreds to check the sender's pid/uid/gid before trusting
+// whatever it sends, since any local process that guesses the socket
+// path could otherwise inject file descriptors into the handshake.
+//
+// PutViaHelper and GetViaHelper offer a variant of this for when the two
+// sides aren't already connected processes with a shared socket: they
+// spawn a small helper over a socketpair to bootstrap the transfer
+// across a setuid boundary or through a supervisor.
 package fd
 
 import (
+	"errors"
 	"net"
 	"os"
-	"syscall"
 )
 
+// ErrUnsupported is returned by APIs in this package that have no
+// implementation on the current platform.
+var ErrUnsupported = errors.New("fd: not supported on this platform")
+
 // Get receives file descriptors over Unix domain socket.
 //
 // Num specifies the expected number of file descriptors in one message.
@@ -32,47 +47,23 @@ import (
 //
 // Use net.FileConn() if you're receiving a network connection. Don't
 // forget to close the returned *os.File though.
+//
+// Received fds are marked close-on-exec before Get returns. On Linux
+// this happens inside the recvmsg call itself, so a concurrent fork+exec
+// elsewhere in the process can't inherit them; on other platforms it
+// happens right after recvmsg returns, and a fork+exec in between can.
+//
+// Get is a thin wrapper around NewConn and Conn.Get for one-off
+// transfers; if you're calling Get or Put repeatedly on the same
+// connection, use NewConn instead to avoid dup'ing via on every call.
 func Get(via *net.UnixConn, num int, filenames []string) ([]*os.File, error) {
-	if num < 1 {
-		return nil, nil
-	}
-
-	// get the underlying socket
-	viaf, err := via.File()
-	if err != nil {
-		return nil, err
-	}
-	socket := int(viaf.Fd())
-	defer viaf.Close()
-
-	// recvmsg
-	buf := make([]byte, syscall.CmsgSpace(num*4))
-	_, _, _, _, err = syscall.Recvmsg(socket, nil, buf, 0)
+	c, err := NewConn(via)
 	if err != nil {
 		return nil, err
 	}
+	defer c.Close()
 
-	// parse control msgs
-	var msgs []syscall.SocketControlMessage
-	msgs, err = syscall.ParseSocketControlMessage(buf)
-
-	// convert fds to files
-	res := make([]*os.File, 0, len(msgs))
-	for i := 0; i < len(msgs) && err == nil; i++ {
-		var fds []int
-		fds, err = syscall.ParseUnixRights(&msgs[i])
-
-		for fi, fd := range fds {
-			var filename string
-			if fi < len(filenames) {
-				filename = filenames[fi]
-			}
-
-			res = append(res, os.NewFile(uintptr(fd), filename))
-		}
-	}
-
-	return res, err
+	return c.Get(num, filenames)
 }
 
 // Put file descriptors into Unix domain socket.
@@ -80,23 +71,16 @@ func Get(via *net.UnixConn, num int, filenames []string) ([]*os.File, error) {
 // Please note that the number of descriptors in one message is limited
 // and is rather small.
 // Use conn.File() to get a file if you want to put a network connection.
+//
+// Put is a thin wrapper around NewConn and Conn.Put for one-off
+// transfers; if you're calling Get or Put repeatedly on the same
+// connection, use NewConn instead to avoid dup'ing via on every call.
 func Put(via *net.UnixConn, files ...*os.File) error {
-	if len(files) == 0 {
-		return nil
-	}
-
-	viaf, err := via.File()
+	c, err := NewConn(via)
 	if err != nil {
 		return err
 	}
-	socket := int(viaf.Fd())
-	defer viaf.Close()
-
-	fds := make([]int, len(files))
-	for i := range files {
-		fds[i] = int(files[i].Fd())
-	}
+	defer c.Close()
 
-	rights := syscall.UnixRights(fds...)
-	return syscall.Sendmsg(socket, nil, rights, nil, 0)
+	return c.Put(files...)
 }
\ No newline at end of file
@@ -0,0 +1,14 @@
+//go:build linux
+
+package fd
+
+import "syscall"
+
+// recvmsgFlags is passed to every Recvmsg call. On Linux, MSG_CMSG_CLOEXEC
+// marks fds close-on-exec as part of the recvmsg syscall itself, so there
+// is no window where a concurrent fork+exec elsewhere in the process could
+// leak them into a child.
+const recvmsgFlags = syscall.MSG_CMSG_CLOEXEC
+
+// closeOnExec is a no-op on Linux: recvmsgFlags already took care of it.
+func closeOnExec(fds []int) {}
@@ -0,0 +1,24 @@
+//go:build unix && !linux
+
+package fd
+
+import "syscall"
+
+// recvmsgFlags is passed to every Recvmsg call. This platform has no
+// equivalent of Linux's MSG_CMSG_CLOEXEC, so fds come back without
+// close-on-exec set and closeOnExec must fix that up afterwards.
+const recvmsgFlags = 0
+
+// closeOnExec marks each fd close-on-exec after the fact, mirroring what
+// the Go stdlib's net.UnixConn.ReadMsgUnix does internally on platforms
+// without MSG_CMSG_CLOEXEC. It holds ForkLock for reading while it sets
+// the flags, but the fds are already open from the moment Recvmsg
+// returns, so a fork that runs between that and this call still copies
+// them into the child.
+func closeOnExec(fds []int) {
+	syscall.ForkLock.RLock()
+	for _, fdv := range fds {
+		syscall.CloseOnExec(fdv)
+	}
+	syscall.ForkLock.RUnlock()
+}
@@ -0,0 +1,116 @@
+//go:build unix
+
+package fd
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// socketpair returns two ends of a connected SOCK_STREAM unix socket,
+// closed automatically at the end of the test.
+func socketpair(t *testing.T) (*net.UnixConn, *net.UnixConn) {
+	t.Helper()
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("socketpair: %v", err)
+	}
+
+	a := fileToUnixConn(t, fds[0])
+	b := fileToUnixConn(t, fds[1])
+	t.Cleanup(func() { a.Close(); b.Close() })
+	return a, b
+}
+
+func fileToUnixConn(t *testing.T, fd int) *net.UnixConn {
+	t.Helper()
+
+	f := os.NewFile(uintptr(fd), "fd-test")
+	conn, err := net.FileConn(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("FileConn: %v", err)
+	}
+
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		t.Fatalf("unexpected conn type %T", conn)
+	}
+	return uc
+}
+
+func TestPutGetRoundTrip(t *testing.T) {
+	a, b := socketpair(t)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if _, err := w.WriteString("hello"); err != nil {
+		t.Fatalf("WriteString: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- Put(a, r) }()
+
+	got, err := Get(b, 1, []string{"piped"})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Get returned %d files, want 1", len(got))
+	}
+	defer got[0].Close()
+
+	if got[0].Name() != "piped" {
+		t.Fatalf("got[0].Name() = %q, want %q", got[0].Name(), "piped")
+	}
+
+	buf := make([]byte, 5)
+	if _, err := got[0].Read(buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(buf) != "hello" {
+		t.Fatalf("read %q, want %q", buf, "hello")
+	}
+}
+
+func TestGetMarksCloseOnExec(t *testing.T) {
+	a, b := socketpair(t)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- Put(a, r) }()
+
+	got, err := Get(b, 1, nil)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	defer got[0].Close()
+
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, got[0].Fd(), syscall.F_GETFD, 0)
+	if errno != 0 {
+		t.Fatalf("fcntl(F_GETFD): %v", errno)
+	}
+	if flags&syscall.FD_CLOEXEC == 0 {
+		t.Fatalf("fd returned by Get is not marked close-on-exec")
+	}
+}
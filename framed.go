@@ -0,0 +1,240 @@
+//go:build unix
+
+package fd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+)
+
+// MaxFDsPerMessage is the practical upper bound on how many file
+// descriptors the kernel will let through a single SCM_RIGHTS control
+// message (253 on Linux). PutFramed chunks files into messages no
+// larger than this.
+const MaxFDsPerMessage = 253
+
+// frameHasFDs is set in a frame's header when the frame carries an
+// SCM_RIGHTS control message rather than plain payload bytes.
+const frameHasFDs = uint32(1) << 31
+const frameCountMask = frameHasFDs - 1
+
+// requireStreamSocket rejects datagram sockets. PutFramed/GetFramed rely
+// on the payload and each fd chunk arriving as independent sendmsg/recvmsg
+// calls while still being delivered as one continuous, ordered byte
+// stream, which only SOCK_STREAM guarantees. Over a SOCK_DGRAM conn
+// (network "unixgram") each recvmsg instead consumes the next queued
+// datagram wholesale, silently discarding whichever frame it didn't ask
+// for (oob included) and hanging forever on the frame it expected next.
+func requireStreamSocket(via *net.UnixConn) error {
+	rc, err := via.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var typ int
+	var sockErr error
+	if err := rc.Control(func(fd uintptr) {
+		typ, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_TYPE)
+	}); err != nil {
+		return err
+	}
+	if sockErr != nil {
+		return sockErr
+	}
+	if typ != syscall.SOCK_STREAM {
+		return fmt.Errorf("fd: PutFramed/GetFramed require a SOCK_STREAM unix socket, got socket type %d", typ)
+	}
+
+	return nil
+}
+
+// PutFramed sends payload and files over via as a single logical
+// message, chunking files across as many SCM_RIGHTS messages as needed
+// when there are more of them than MaxFDsPerMessage. GetFramed
+// reassembles whatever PutFramed sent, regardless of how many
+// underlying sendmsg/recvmsg calls the kernel required.
+//
+// This is meant for handshakes that need to hand over hundreds of
+// inherited listeners plus a state blob in one call, which Put alone
+// cannot do since the caller would have to know in advance how many fds
+// fit in a single SCM_RIGHTS message.
+func PutFramed(via *net.UnixConn, payload []byte, files ...*os.File) error {
+	if uint32(len(payload)) > frameCountMask || uint32(len(files)) > frameCountMask {
+		return fmt.Errorf("fd: PutFramed: too much payload or too many files to frame")
+	}
+	if err := requireStreamSocket(via); err != nil {
+		return err
+	}
+
+	c, err := NewConn(via)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	header := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], uint32(len(files)))
+	copy(header[8:], payload)
+
+	if err := sendFrame(c, header, nil); err != nil {
+		return err
+	}
+
+	for len(files) > 0 {
+		n := len(files)
+		if n > MaxFDsPerMessage {
+			n = MaxFDsPerMessage
+		}
+
+		fds := make([]int, n)
+		for i, f := range files[:n] {
+			fds[i] = int(f.Fd())
+		}
+
+		frameHeader := make([]byte, 4)
+		binary.BigEndian.PutUint32(frameHeader, frameHasFDs|uint32(n))
+
+		rights := syscall.UnixRights(fds...)
+		if err := sendFrame(c, frameHeader, rights); err != nil {
+			return err
+		}
+
+		files = files[n:]
+	}
+
+	return nil
+}
+
+// sendFrame writes b and (optionally) oob through c's RawConn, so via's
+// own fd stays non-blocking and usable for other I/O the way Conn.Put/
+// Conn.Get already do. A single sendmsg call may accept fewer bytes than
+// len(b) — routine on a non-blocking socket once b is larger than the
+// socket's send buffer — so sendFrame loops until all of b is sent. oob
+// is attached to the first sendmsg call only; the kernel doesn't expect
+// or want it resent with the remaining bytes.
+func sendFrame(c *Conn, b, oob []byte) error {
+	for first := true; first || len(b) > 0; first = false {
+		var n int
+		var sendErr error
+		if err := c.rc.Write(func(fd uintptr) bool {
+			n, sendErr = syscall.SendmsgN(int(fd), b, oob, nil, 0)
+			return sendErr != syscall.EAGAIN
+		}); err != nil {
+			return err
+		}
+		if sendErr != nil {
+			return sendErr
+		}
+		b = b[n:]
+		oob = nil
+	}
+	return nil
+}
+
+// recvFrame reads through c's RawConn until b is completely filled,
+// returning the ancillary data (if any) read alongside it. A single
+// recvmsg call may return fewer bytes than len(b), so recvFrame loops;
+// oob is only meaningful on the call that reads b's first byte, since
+// that's where the kernel attaches it, so later calls pass no oob
+// buffer of their own. recvFrame always performs at least one recvmsg
+// call, even when len(b) is 0, since callers like getWithCreds pass no
+// payload buffer and only want the oob data a single recvmsg returns.
+func recvFrame(c *Conn, b, oob []byte) (oobn int, err error) {
+	read := 0
+	for first := true; first || read < len(b); first = false {
+		var n, rOobn int
+		var recvErr error
+		if werr := c.rc.Read(func(fd uintptr) bool {
+			n, rOobn, _, _, recvErr = syscall.Recvmsg(int(fd), b[read:], oob, recvmsgFlags)
+			return recvErr != syscall.EAGAIN
+		}); werr != nil {
+			return oobn, werr
+		}
+		if recvErr != nil {
+			return oobn, recvErr
+		}
+		if n == 0 && len(b) > 0 {
+			return oobn, io.ErrUnexpectedEOF
+		}
+
+		if read == 0 {
+			oobn = rOobn
+		}
+		read += n
+		oob = nil
+	}
+	return oobn, nil
+}
+
+// GetFramed receives a payload and files sent via PutFramed, reassembling
+// them regardless of how many SCM_RIGHTS messages the kernel split the
+// file descriptors across.
+func GetFramed(via *net.UnixConn) ([]*os.File, []byte, error) {
+	if err := requireStreamSocket(via); err != nil {
+		return nil, nil, err
+	}
+
+	c, err := NewConn(via)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c.Close()
+
+	header := make([]byte, 8)
+	if _, err := recvFrame(c, header, nil); err != nil {
+		return nil, nil, err
+	}
+
+	payloadLen := binary.BigEndian.Uint32(header[0:4])
+	numFiles := binary.BigEndian.Uint32(header[4:8])
+
+	var payload []byte
+	if payloadLen > 0 {
+		payload = make([]byte, payloadLen)
+		if _, err := recvFrame(c, payload, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var allFDs []int
+	for uint32(len(allFDs)) < numFiles {
+		frameHeader := make([]byte, 4)
+		oob := make([]byte, syscall.CmsgSpace(int(numFiles)*4))
+
+		oobn, err := recvFrame(c, frameHeader, oob)
+		if err != nil {
+			return nil, payload, err
+		}
+
+		flags := binary.BigEndian.Uint32(frameHeader)
+		if flags&frameHasFDs == 0 {
+			return nil, payload, fmt.Errorf("fd: GetFramed: expected an fd frame")
+		}
+
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			return nil, payload, err
+		}
+
+		for i := range msgs {
+			fds, err := syscall.ParseUnixRights(&msgs[i])
+			if err != nil {
+				return nil, payload, err
+			}
+			allFDs = append(allFDs, fds...)
+		}
+	}
+	closeOnExec(allFDs)
+
+	files := make([]*os.File, len(allFDs))
+	for i, fdv := range allFDs {
+		files[i] = os.NewFile(uintptr(fdv), "")
+	}
+
+	return files, payload, nil
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package fd
+
+import (
+	"net"
+	"os"
+)
+
+// PutFramed is not implemented on this platform; it relies on
+// SCM_RIGHTS, which is unix-specific.
+func PutFramed(via *net.UnixConn, payload []byte, files ...*os.File) error {
+	return ErrUnsupported
+}
+
+// GetFramed is not implemented on this platform; it relies on
+// SCM_RIGHTS, which is unix-specific.
+func GetFramed(via *net.UnixConn) ([]*os.File, []byte, error) {
+	return nil, nil, ErrUnsupported
+}
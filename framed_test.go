@@ -0,0 +1,116 @@
+//go:build unix
+
+package fd
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestPutGetFramedRoundTrip(t *testing.T) {
+	a, b := socketpair(t)
+
+	r1, w1, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %v", err)
+	}
+	defer r1.Close()
+	defer w1.Close()
+	if _, err := w1.WriteString("one"); err != nil {
+		t.Fatalf("WriteString: %v", err)
+	}
+
+	r2, w2, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %v", err)
+	}
+	defer r2.Close()
+	defer w2.Close()
+	if _, err := w2.WriteString("two"); err != nil {
+		t.Fatalf("WriteString: %v", err)
+	}
+
+	payload := []byte("state-blob")
+
+	done := make(chan error, 1)
+	go func() { done <- PutFramed(a, payload, r1, r2) }()
+
+	files, gotPayload, err := GetFramed(b)
+	if err != nil {
+		t.Fatalf("GetFramed: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("PutFramed: %v", err)
+	}
+
+	if !bytes.Equal(gotPayload, payload) {
+		t.Fatalf("payload = %q, want %q", gotPayload, payload)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	defer files[0].Close()
+	defer files[1].Close()
+
+	buf := make([]byte, 3)
+	if _, err := files[0].Read(buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(buf) != "one" {
+		t.Fatalf("files[0] read %q, want %q", buf, "one")
+	}
+	if _, err := files[1].Read(buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(buf) != "two" {
+		t.Fatalf("files[1] read %q, want %q", buf, "two")
+	}
+}
+
+// TestPutGetFramedLargePayload exercises a payload bigger than the
+// socket's send/receive buffers, which forces sendmsg/recvmsg to split
+// it across multiple calls. sendFrame/recvFrame must loop until every
+// byte is transferred rather than silently truncating the message.
+func TestPutGetFramedLargePayload(t *testing.T) {
+	a, b := socketpair(t)
+
+	payload := make([]byte, 8<<20)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- PutFramed(a, payload) }()
+
+	_, gotPayload, err := GetFramed(b)
+	if err != nil {
+		t.Fatalf("GetFramed: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("PutFramed: %v", err)
+	}
+
+	if !bytes.Equal(gotPayload, payload) {
+		t.Fatalf("got %d bytes back, want %d, and/or contents differ", len(gotPayload), len(payload))
+	}
+}
+
+func TestFramedRejectsDatagramSocket(t *testing.T) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		t.Fatalf("socketpair: %v", err)
+	}
+	a := fileToUnixConn(t, fds[0])
+	b := fileToUnixConn(t, fds[1])
+	defer a.Close()
+	defer b.Close()
+
+	if err := PutFramed(a, nil); err == nil {
+		t.Fatal("expected PutFramed to reject a SOCK_DGRAM unix socket")
+	}
+	if _, _, err := GetFramed(b); err == nil {
+		t.Fatal("expected GetFramed to reject a SOCK_DGRAM unix socket")
+	}
+}
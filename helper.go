@@ -0,0 +1,125 @@
+package fd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+)
+
+// helperSocketEnv tells a helper process spawned by PutViaHelper or
+// GetViaHelper which of its inherited file descriptors (always fd 3,
+// right after stdin/stdout/stderr) is its end of the socketpair to use
+// for the acknowledgement handshake.
+const helperSocketEnv = "FD_HELPER_SOCKET_FD"
+
+// helperNumFilesEnv tells a helper spawned by PutViaHelper how many
+// files, starting at fd 4 (right after the socketpair fd), were
+// attached to it via ExtraFiles.
+const helperNumFilesEnv = "FD_HELPER_NUM_FILES"
+
+// PutViaHelper hands files to helperPath by attaching them directly to
+// the child's inherited file descriptors, the same technique fusermount
+// uses to hand a mounted fd to the process that invoked it. No
+// sendmsg/SCM_RIGHTS is needed for this direction: the files already
+// exist in this process, so plain fd inheritance via exec.Cmd.ExtraFiles
+// carries them across the fork+exec. The helper is told via
+// helperSocketEnv and helperNumFilesEnv which fd is the ack socketpair
+// and how many files follow it, and is expected to write a single
+// acknowledgement byte back to the socketpair once it has consumed them,
+// before exiting.
+//
+// This lets the package bootstrap fd transfer across a setuid boundary
+// or through a supervisor, which the in-process Put/Get API cannot do.
+// The acknowledgement travels over a Unix socketpair, so on platforms
+// outside unix PutViaHelper and GetViaHelper return ErrUnsupported.
+func PutViaHelper(helperPath string, files ...*os.File) error {
+	parent, child, err := newHelperSocketpair()
+	if err != nil {
+		return err
+	}
+	defer parent.Close()
+
+	cmd, err := startHelper(helperPath, child, files)
+	if err != nil {
+		return err
+	}
+
+	if err := readAck(parent); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	return cmd.Wait()
+}
+
+// GetViaHelper is the inverse of PutViaHelper. Here the files don't
+// exist in this process yet, so plain fd inheritance can't hand them
+// over — only the helper can create or open them, and the only way for
+// a child to pass a freshly created fd back to its parent is over an
+// already-open channel via SCM_RIGHTS. So GetViaHelper spawns helperPath
+// with its end of a socketpair, receives num files from it via Get,
+// sends back the acknowledgement byte PutViaHelper's counterpart
+// expects, and waits for the helper to exit.
+func GetViaHelper(helperPath string, num int, filenames []string) ([]*os.File, error) {
+	parent, child, err := newHelperSocketpair()
+	if err != nil {
+		return nil, err
+	}
+	defer parent.Close()
+
+	cmd, err := startHelper(helperPath, child, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := Get(parent, num, filenames)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	if err := writeAck(parent); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return files, err
+	}
+
+	return files, cmd.Wait()
+}
+
+// startHelper launches helperPath with child as fd 3 and, for
+// PutViaHelper, files attached right after it starting at fd 4.
+// helperSocketEnv and helperNumFilesEnv tell the helper where to find
+// each.
+func startHelper(helperPath string, child *os.File, files []*os.File) (*exec.Cmd, error) {
+	cmd := exec.Command(helperPath)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=3", helperSocketEnv),
+		fmt.Sprintf("%s=%d", helperNumFilesEnv, len(files)),
+	)
+	cmd.ExtraFiles = append([]*os.File{child}, files...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Start()
+	child.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
+func readAck(via *net.UnixConn) error {
+	ack := make([]byte, 1)
+	_, err := via.Read(ack)
+	return err
+}
+
+func writeAck(via *net.UnixConn) error {
+	_, err := via.Write([]byte{1})
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package fd
+
+import (
+	"net"
+	"os"
+)
+
+// newHelperSocketpair is not implemented on this platform: it relies on
+// syscall.Socketpair(AF_UNIX, ...), which is unix-specific. PutViaHelper
+// and GetViaHelper report ErrUnsupported here rather than failing to
+// build, so callers on other platforms still compile against them.
+func newHelperSocketpair() (parent *net.UnixConn, child *os.File, err error) {
+	return nil, nil, ErrUnsupported
+}
@@ -0,0 +1,97 @@
+package fd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// buildFDHelper compiles testdata/fdhelper into t.TempDir() and returns
+// its path, so tests can exercise PutViaHelper/GetViaHelper against a
+// real helper process instead of only the missing-binary/ack-failure
+// error paths.
+func buildFDHelper(t *testing.T) string {
+	t.Helper()
+
+	bin := filepath.Join(t.TempDir(), "fdhelper")
+	cmd := exec.Command("go", "build", "-o", bin, "./testdata/fdhelper")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Skipf("building testdata/fdhelper: %v\n%s", err, out)
+	}
+	return bin
+}
+
+func TestPutViaHelperMissingBinary(t *testing.T) {
+	if err := PutViaHelper("/nonexistent-fd-helper-binary"); err == nil {
+		t.Fatal("expected an error for a missing helper binary")
+	}
+}
+
+func TestGetViaHelperMissingBinary(t *testing.T) {
+	if _, err := GetViaHelper("/nonexistent-fd-helper-binary", 1, nil); err == nil {
+		t.Fatal("expected an error for a missing helper binary")
+	}
+}
+
+// TestPutViaHelperReapsOnAckFailure exercises the error path where the
+// helper exits without ever writing the ack: PutViaHelper must still
+// return promptly (not hang) and must not leave a zombie behind, which
+// requires calling cmd.Wait() after cmd.Process.Kill() on this path.
+func TestPutViaHelperReapsOnAckFailure(t *testing.T) {
+	if err := PutViaHelper("/usr/bin/true"); err == nil {
+		t.Fatal("expected an error since /usr/bin/true never acks")
+	}
+}
+
+// TestPutViaHelperRealTransfer exercises a real, successful transfer
+// through testdata/fdhelper rather than just the missing-binary and
+// ack-failure error paths: it checks the helper actually receives the
+// inherited files (not just the ack socketpair) and can read their
+// contents.
+func TestPutViaHelperRealTransfer(t *testing.T) {
+	helper := buildFDHelper(t)
+	t.Setenv("FD_HELPER_MODE", "put")
+	t.Setenv("FD_HELPER_WANT_CONTENT", "hello from the parent")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %v", err)
+	}
+	defer r.Close()
+	if _, err := w.WriteString("hello from the parent"); err != nil {
+		t.Fatalf("WriteString: %v", err)
+	}
+	w.Close()
+
+	if err := PutViaHelper(helper, r); err != nil {
+		t.Fatalf("PutViaHelper: %v", err)
+	}
+}
+
+// TestGetViaHelperRealTransfer is the inverse: it checks that a real
+// helper process can hand a freshly created file back to the parent via
+// SCM_RIGHTS and that GetViaHelper returns its actual contents.
+func TestGetViaHelperRealTransfer(t *testing.T) {
+	helper := buildFDHelper(t)
+	t.Setenv("FD_HELPER_MODE", "get")
+	t.Setenv("FD_HELPER_WANT_CONTENT", "hello from the helper")
+
+	files, err := GetViaHelper(helper, 1, nil)
+	if err != nil {
+		t.Fatalf("GetViaHelper: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d files, want 1", len(files))
+	}
+	defer files[0].Close()
+
+	buf := make([]byte, len("hello from the helper"))
+	if _, err := files[0].Read(buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(buf) != "hello from the helper" {
+		t.Fatalf("read %q, want %q", buf, "hello from the helper")
+	}
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package fd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// newHelperSocketpair creates a connected pair of Unix sockets: parent
+// stays in this process as a *net.UnixConn, child is handed to the
+// helper via ExtraFiles.
+func newHelperSocketpair() (parent *net.UnixConn, child *os.File, err error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parentFile := os.NewFile(uintptr(fds[0]), "fd-helper-parent")
+	child = os.NewFile(uintptr(fds[1]), "fd-helper-child")
+
+	parentConn, err := net.FileConn(parentFile)
+	parentFile.Close()
+	if err != nil {
+		child.Close()
+		return nil, nil, err
+	}
+
+	parent, ok := parentConn.(*net.UnixConn)
+	if !ok {
+		parentConn.Close()
+		child.Close()
+		return nil, nil, fmt.Errorf("fd: newHelperSocketpair: unexpected conn type %T", parentConn)
+	}
+
+	return parent, child, nil
+}
@@ -0,0 +1,98 @@
+// Command fdhelper is a minimal stand-in for a real fusermount-style
+// helper binary, used by helper_test.go to exercise PutViaHelper and
+// GetViaHelper against a process that actually speaks the protocol
+// instead of just exiting or never acking.
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	fd "github.com/eiuapp/fd"
+)
+
+func main() {
+	sockFD, err := strconv.Atoi(os.Getenv("FD_HELPER_SOCKET_FD"))
+	if err != nil {
+		fail("bad FD_HELPER_SOCKET_FD: %v", err)
+	}
+
+	sockFile := os.NewFile(uintptr(sockFD), "fd-helper-socket")
+	c, err := net.FileConn(sockFile)
+	sockFile.Close()
+	if err != nil {
+		fail("FileConn: %v", err)
+	}
+	conn := c.(*net.UnixConn)
+
+	switch os.Getenv("FD_HELPER_MODE") {
+	case "put":
+		runPut(conn)
+	case "get":
+		runGet(conn)
+	default:
+		fail("FD_HELPER_MODE must be %q or %q", "put", "get")
+	}
+}
+
+// runPut plays the PutViaHelper side: the transferred files are already
+// open on fds 4..4+n-1 via ExtraFiles, so it just reads and checks them,
+// then acks.
+func runPut(conn *net.UnixConn) {
+	numFiles, err := strconv.Atoi(os.Getenv("FD_HELPER_NUM_FILES"))
+	if err != nil {
+		fail("bad FD_HELPER_NUM_FILES: %v", err)
+	}
+	want := os.Getenv("FD_HELPER_WANT_CONTENT")
+
+	for i := 0; i < numFiles; i++ {
+		f := os.NewFile(uintptr(4+i), "")
+		got, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			fail("read inherited file %d: %v", i, err)
+		}
+		if string(got) != want {
+			fail("inherited file %d = %q, want %q", i, got, want)
+		}
+	}
+
+	if _, err := conn.Write([]byte{1}); err != nil {
+		fail("write ack: %v", err)
+	}
+}
+
+// runGet plays the GetViaHelper side: it has no pre-existing files to
+// hand over, so it creates one and sends it to the parent over the
+// socketpair via the package's own SCM_RIGHTS-based Put, then waits for
+// the parent's ack.
+func runGet(conn *net.UnixConn) {
+	content := os.Getenv("FD_HELPER_WANT_CONTENT")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		fail("pipe: %v", err)
+	}
+	if _, err := w.WriteString(content); err != nil {
+		fail("write pipe: %v", err)
+	}
+	w.Close()
+
+	if err := fd.Put(conn, r); err != nil {
+		fail("Put: %v", err)
+	}
+	r.Close()
+
+	ack := make([]byte, 1)
+	if _, err := conn.Read(ack); err != nil {
+		fail("read ack: %v", err)
+	}
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "fdhelper: "+format+"\n", args...)
+	os.Exit(1)
+}